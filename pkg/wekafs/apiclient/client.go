@@ -0,0 +1,60 @@
+package apiclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ApiClient fields introduced by the NFS/SMB interface-group selection,
+// health-tracking and background-refresh work: the cached interface group
+// maps, their guarding locks, the configured selection strategy, and the
+// optional health tracker/refresher subsystems. The REST transport side of
+// ApiClient (credentials, HTTP plumbing, etc.) is intentionally not
+// duplicated here.
+type ApiClient struct {
+	NfsInterfaceGroups map[string]*InterfaceGroup
+	SmbInterfaceGroups map[string]*InterfaceGroup
+
+	nfsInterfaceGroupsMu sync.RWMutex
+	smbInterfaceGroupsMu sync.RWMutex
+
+	// NfsIpSelectionStrategy controls how GetNfsMountIp/GetSmbMountIp pick an
+	// IP out of a cached interface group. DefaultNfsIpSelectionStrategy is
+	// used when this is left at its zero value.
+	NfsIpSelectionStrategy NfsIpSelectionStrategy
+
+	healthTracker *HealthTracker
+	refresher     *Refresher
+}
+
+// cachedInterfaceGroups returns every interface group currently cached on
+// the client, NFS and SMB alike, for subsystems (health probing, refresh)
+// that need to operate over all of them regardless of protocol.
+func (a *ApiClient) cachedInterfaceGroups() []*InterfaceGroup {
+	a.nfsInterfaceGroupsMu.RLock()
+	igs := make([]*InterfaceGroup, 0, len(a.NfsInterfaceGroups)+len(a.SmbInterfaceGroups))
+	for _, ig := range a.NfsInterfaceGroups {
+		igs = append(igs, ig)
+	}
+	a.nfsInterfaceGroupsMu.RUnlock()
+
+	a.smbInterfaceGroupsMu.RLock()
+	for _, ig := range a.SmbInterfaceGroups {
+		igs = append(igs, ig)
+	}
+	a.smbInterfaceGroupsMu.RUnlock()
+
+	return igs
+}
+
+// EnableHealthTracking creates a HealthTracker, attaches it to the client so
+// GetNfsMountIp starts filtering on health, and starts its background probe
+// loop. probeTimeout/probeInterval <= 0 fall back to their package defaults.
+func (a *ApiClient) EnableHealthTracking(ctx context.Context, probeTimeout, probeInterval time.Duration, onUnhealthy UnhealthyIpCallback) *HealthTracker {
+	tracker := NewHealthTracker(probeTimeout)
+	tracker.OnUnhealthy = onUnhealthy
+	a.healthTracker = tracker
+	tracker.Start(ctx, a, probeInterval)
+	return tracker
+}
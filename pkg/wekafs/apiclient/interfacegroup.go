@@ -6,10 +6,41 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"k8s.io/helm/pkg/urlutil"
+	"net"
 	"os"
 	"sort"
+	"sync"
+	"sync/atomic"
 )
 
+// NfsIpSelectionStrategy controls how ApiClient picks an IP address out of an
+// InterfaceGroup when more than one candidate is available.
+type NfsIpSelectionStrategy string
+
+const (
+	// NfsIpSelectionStrategyHash deterministically hashes the local hostname
+	// to always pick the same IP for a given node. This is the legacy, and
+	// default, behavior.
+	NfsIpSelectionStrategyHash NfsIpSelectionStrategy = "Hash"
+	// NfsIpSelectionStrategyLocalitySubnet prefers IPs that share a subnet
+	// with one of the node's own network interfaces, falling back to
+	// NfsIpSelectionStrategyHash when no locality match is found.
+	NfsIpSelectionStrategyLocalitySubnet NfsIpSelectionStrategy = "LocalitySubnet"
+	// NfsIpSelectionStrategyRoundRobin cycles through the available IPs using
+	// the client's HealthTracker counter. Without a HealthTracker it behaves
+	// like NfsIpSelectionStrategyHash.
+	NfsIpSelectionStrategyRoundRobin NfsIpSelectionStrategy = "RoundRobin"
+	// NfsIpSelectionStrategyRandom picks a random IP on every call, weighted
+	// by the client's HealthTracker towards IPs with fewer consecutive probe
+	// failures. Without a HealthTracker it behaves like
+	// NfsIpSelectionStrategyHash.
+	NfsIpSelectionStrategyRandom NfsIpSelectionStrategy = "Random"
+)
+
+// DefaultNfsIpSelectionStrategy is used whenever ApiClient.NfsIpSelectionStrategy
+// is left at its zero value.
+const DefaultNfsIpSelectionStrategy = NfsIpSelectionStrategyHash
+
 type InterfaceGroupType string
 
 const (
@@ -26,6 +57,10 @@ type InterfaceGroup struct {
 	Type            InterfaceGroupType `json:"type"`
 	Gateway         string             `json:"gateway"`
 	Status          string             `json:"status"`
+	// SmbDomain and SmbRequiresCredentials are only populated for
+	// InterfaceGroupTypeSMB, surfaced as-is from the Weka API payload.
+	SmbDomain              string `json:"smb_domain,omitempty"`
+	SmbRequiresCredentials bool   `json:"smb_requires_credentials,omitempty"`
 }
 
 func (i *InterfaceGroup) String() string {
@@ -68,23 +103,268 @@ func (i *InterfaceGroup) isSmb() bool {
 	return i.getInterfaceGroupType() == InterfaceGroupTypeSMB
 }
 
-// GetIpAddress returns a single IP address based on hostname, so for same server, always same IP address will be returned
-// This is useful for NFS mount, where we need to have same IP address for same server
+// RequiresSmbCredentials reports whether this SMB interface group requires
+// domain credentials to mount, as surfaced by the Weka API. It is always
+// false for non-SMB interface groups.
+func (i *InterfaceGroup) RequiresSmbCredentials() bool {
+	return i.isSmb() && i.SmbRequiresCredentials
+}
+
+// localSubnets returns the CIDR networks of every IPv4/IPv6 address
+// configured on this node's own network interfaces.
+func localSubnets() ([]*net.IPNet, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var nets []*net.IPNet
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				nets = append(nets, ipNet)
+			}
+		}
+	}
+	return nets, nil
+}
+
+// subnetFor returns the network obtained by combining ip with this interface
+// group's SubnetMask, or nil if either fails to parse.
+func (i *InterfaceGroup) subnetFor(ip string) *net.IPNet {
+	parsedIp := net.ParseIP(ip)
+	mask := net.ParseIP(i.SubnetMask)
+	if parsedIp == nil || mask == nil {
+		return nil
+	}
+	var ipMask net.IPMask
+	if v4 := mask.To4(); v4 != nil {
+		ipMask = net.IPMask(v4)
+	} else {
+		ipMask = net.IPMask(mask.To16())
+	}
+	return &net.IPNet{IP: parsedIp.Mask(ipMask), Mask: ipMask}
+}
+
+// localityMatchedIps filters this interface group's IPs down to the ones
+// whose subnet (per SubnetMask) overlaps with one of localNets, mirroring
+// the "choose host interface" pattern used by kube-proxy.
+func (i *InterfaceGroup) localityMatchedIps(localNets []*net.IPNet) []string {
+	var matched []string
+	for _, ip := range i.Ips {
+		candidateNet := i.subnetFor(ip)
+		if candidateNet == nil {
+			continue
+		}
+		for _, local := range localNets {
+			if candidateNet.Contains(local.IP) || local.Contains(candidateNet.IP) {
+				matched = append(matched, ip)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// intersectIps returns the elements of a that also appear in b, preserving
+// a's order.
+func intersectIps(a, b []string) []string {
+	set := make(map[string]struct{}, len(b))
+	for _, ip := range b {
+		set[ip] = struct{}{}
+	}
+	var out []string
+	for _, ip := range a {
+		if _, ok := set[ip]; ok {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
+
+// GetIpAddress returns a single IP address from the interface group, chosen
+// according to strategy. If tracker is non-nil, candidates are first narrowed
+// to the IPs it currently considers healthy (falling back to the full list if
+// none are healthy, so a stale/empty tracker never blocks mounting entirely).
+//
+// NfsIpSelectionStrategyLocalitySubnet further narrows the candidates to IPs
+// that share a subnet with one of the node's own network interfaces, falling
+// back to the unfiltered candidate set if no locality match is found.
+// NfsIpSelectionStrategyRoundRobin and NfsIpSelectionStrategyRandom delegate
+// to tracker's corresponding IpSelectionPolicy when a tracker is supplied;
+// otherwise, and for NfsIpSelectionStrategyHash, the deterministic
+// hostname-hash tiebreak is applied, so for the same server the same IP
+// address is always returned.
 // TODO: this could be further optimized in future to avoid a situation where multiple servers are not evenly distributed
 // and some IPs are getting more load than others. Could be done, for example, by random selection of IP address etc.
-func (i *InterfaceGroup) GetIpAddress() (string, error) {
+func (i *InterfaceGroup) GetIpAddress(strategy NfsIpSelectionStrategy, tracker *HealthTracker) (string, error) {
 	if len(i.Ips) == 0 {
 		return "", errors.New("no IP addresses found for interface group")
 	}
-	hostname, err := os.Hostname()
-	if err != nil {
-		return "", err
+
+	candidates := i.Ips
+	if tracker != nil {
+		if healthy := tracker.healthyIps(candidates); len(healthy) > 0 {
+			candidates = healthy
+		}
+	}
+
+	if strategy == NfsIpSelectionStrategyLocalitySubnet {
+		if localNets, err := localSubnets(); err == nil {
+			if matched := intersectIps(i.localityMatchedIps(localNets), candidates); len(matched) > 0 {
+				candidates = matched
+			}
+		}
+	}
+
+	var chosen string
+	switch strategy {
+	case NfsIpSelectionStrategyRoundRobin, NfsIpSelectionStrategyRandom:
+		if tracker != nil {
+			policy := IpSelectionPolicyRoundRobin
+			if strategy == NfsIpSelectionStrategyRandom {
+				policy = IpSelectionPolicyWeightedRandom
+			}
+			chosen = tracker.pick(candidates, policy)
+			break
+		}
+		fallthrough
+	default:
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		if hostname == "" {
+			hostname = "localhost"
+		}
+		chosen = candidates[hashString(hostname, len(candidates))]
 	}
-	if hostname == "" {
-		hostname = "localhost"
+
+	if tracker != nil {
+		tracker.markUsed(chosen)
+	}
+	return chosen, nil
+}
+
+// containsIp reports whether ip is present in ips.
+func containsIp(ips []string, ip string) bool {
+	for _, x := range ips {
+		if x == ip {
+			return true
+		}
 	}
+	return false
+}
 
-	return i.Ips[hashString(hostname, len(i.Ips))], nil
+// rotateByHash rotates ips so that the deterministic hash-selected IP for
+// hostname comes first, keeping the relative order of the rest stable. This
+// way a node asking for N IPs always gets the same leading IP as
+// GetIpAddress, and the same fan-out set across calls.
+func rotateByHash(ips []string, hostname string) []string {
+	if len(ips) == 0 {
+		return ips
+	}
+	start := hashString(hostname, len(ips))
+	return append(append([]string{}, ips[start:]...), ips[:start]...)
+}
+
+// reorderByPolicy rotates or shuffles ips per policy, so that
+// GetIpAddresses fans out across IG members on repeated calls instead of
+// always returning the same leading IPs.
+func reorderByPolicy(ips []string, tracker *HealthTracker, policy IpSelectionPolicy) []string {
+	if len(ips) == 0 {
+		return ips
+	}
+	switch policy {
+	case IpSelectionPolicyRoundRobin:
+		n := atomic.AddUint64(&tracker.rrCounter, 1)
+		start := int(n-1) % len(ips)
+		return append(append([]string{}, ips[start:]...), ips[:start]...)
+	case IpSelectionPolicyWeightedRandom:
+		return tracker.weightedOrder(ips)
+	default:
+		return ips
+	}
+}
+
+// GetIpAddresses returns up to n distinct IP addresses from the interface
+// group, chosen by strategy, for NFS clients using nconnect=n or multipath
+// that benefit from spreading connections across multiple server IPs.
+// Locality matches (when strategy is NfsIpSelectionStrategyLocalitySubnet)
+// are ordered first; any remaining slots are filled deterministically (hash
+// mode) or via round-robin/random, exactly as GetIpAddress would pick a
+// single IP. If fewer than n IPs are available, all of them are returned
+// without error.
+func (i *InterfaceGroup) GetIpAddresses(n int, strategy NfsIpSelectionStrategy, tracker *HealthTracker) ([]string, error) {
+	if n <= 0 {
+		return nil, errors.New("n must be greater than zero")
+	}
+	if len(i.Ips) == 0 {
+		return nil, errors.New("no IP addresses found for interface group")
+	}
+
+	pool := i.Ips
+	if tracker != nil {
+		if healthy := tracker.healthyIps(pool); len(healthy) > 0 {
+			pool = healthy
+		}
+	}
+
+	var ordered []string
+	if strategy == NfsIpSelectionStrategyLocalitySubnet {
+		if localNets, err := localSubnets(); err == nil {
+			ordered = intersectIps(i.localityMatchedIps(localNets), pool)
+		}
+	}
+	localCount := len(ordered)
+	for _, ip := range pool {
+		if !containsIp(ordered, ip) {
+			ordered = append(ordered, ip)
+		}
+	}
+
+	switch strategy {
+	case NfsIpSelectionStrategyRoundRobin, NfsIpSelectionStrategyRandom:
+		if tracker != nil {
+			policy := IpSelectionPolicyRoundRobin
+			if strategy == NfsIpSelectionStrategyRandom {
+				policy = IpSelectionPolicyWeightedRandom
+			}
+			ordered = reorderByPolicy(ordered, tracker, policy)
+			break
+		}
+		fallthrough
+	default:
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		if hostname == "" {
+			hostname = "localhost"
+		}
+		// The locality-matched prefix and the remainder are hash-rotated
+		// separately: rotating them as one list would let the rotation push a
+		// local IP past the first n entries, defeating the "locality first"
+		// guarantee above. Rotating the prefix on its own keeps its leading IP
+		// the one GetIpAddress picks.
+		local := rotateByHash(ordered[:localCount], hostname)
+		rest := rotateByHash(ordered[localCount:], hostname)
+		ordered = append(append([]string{}, local...), rest...)
+	}
+
+	if n > len(ordered) {
+		n = len(ordered)
+	}
+	result := append([]string{}, ordered[:n]...)
+	if tracker != nil {
+		for _, ip := range result {
+			tracker.markUsed(ip)
+		}
+	}
+	return result, nil
 }
 
 func (a *ApiClient) GetInterfaceGroups(ctx context.Context, interfaceGroups *[]InterfaceGroup) error {
@@ -122,68 +402,152 @@ func (a *ApiClient) GetInterfaceGroupByUid(ctx context.Context, uid uuid.UUID, i
 	return nil
 }
 
-func (a *ApiClient) fetchNfsInterfaceGroup(ctx context.Context, name *string, useDefault bool) error {
+// fetchInterfaceGroup populates cache (keyed by name, or "default" when
+// useDefault is set) from the cluster for the given interface group type. It
+// is the shared implementation behind fetchNfsInterfaceGroup and
+// fetchSmbInterfaceGroup.
+func (a *ApiClient) fetchInterfaceGroup(ctx context.Context, igType InterfaceGroupType, cache map[string]*InterfaceGroup, mu *sync.RWMutex, name *string, useDefault bool) error {
 	igs := &[]InterfaceGroup{}
-	err := a.GetInterfaceGroupsByType(ctx, InterfaceGroupTypeNFS, igs)
+	err := a.GetInterfaceGroupsByType(ctx, igType, igs)
 	if err != nil {
-		return errors.Join(errors.New("failed to fetch nfs interface groups"), err)
+		return errors.Join(fmt.Errorf("failed to fetch %s interface groups", igType), err)
 	}
 	if len(*igs) == 0 {
-		return errors.New("no nfs interface groups found")
+		return fmt.Errorf("no %s interface groups found", igType)
 	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
 	if name != nil {
-		for _, ig := range *igs {
-			if ig.Name == *name {
-				a.NfsInterfaceGroups[*name] = &ig
+		for idx := range *igs {
+			if (*igs)[idx].Name == *name {
+				cache[*name] = &(*igs)[idx]
+				break
 			}
 		}
 	} else if useDefault {
-		a.NfsInterfaceGroups["default"] = &(*igs)[0]
+		cache["default"] = &(*igs)[0]
 	}
 
 	ig := &InterfaceGroup{}
 	if name != nil {
-		ig = a.NfsInterfaceGroups[*name]
+		ig = cache[*name]
 	} else {
-		ig = a.NfsInterfaceGroups["default"]
+		ig = cache["default"]
 	}
 	if ig == nil {
-		return errors.New("no nfs interface group found")
+		return fmt.Errorf("no %s interface group found", igType)
 	}
 
 	if len(ig.Ips) == 0 {
-		return errors.New("no IP addresses found for nfs interface group")
+		return fmt.Errorf("no IP addresses found for %s interface group", igType)
 	}
 	// Make sure the IPs are always sorted
 	sort.Strings(ig.Ips)
 	return nil
 }
 
-func (a *ApiClient) GetNfsInterfaceGroup(ctx context.Context, name *string) *InterfaceGroup {
+func (a *ApiClient) fetchNfsInterfaceGroup(ctx context.Context, name *string, useDefault bool) error {
+	return a.fetchInterfaceGroup(ctx, InterfaceGroupTypeNFS, a.NfsInterfaceGroups, &a.nfsInterfaceGroupsMu, name, useDefault)
+}
+
+func (a *ApiClient) fetchSmbInterfaceGroup(ctx context.Context, name *string, useDefault bool) error {
+	return a.fetchInterfaceGroup(ctx, InterfaceGroupTypeSMB, a.SmbInterfaceGroups, &a.smbInterfaceGroupsMu, name, useDefault)
+}
+
+// getInterfaceGroup returns the cached interface group for igName, fetching
+// it from the cluster first if it isn't cached yet. It is the shared
+// implementation behind GetNfsInterfaceGroup and GetSmbInterfaceGroup.
+func (a *ApiClient) getInterfaceGroup(ctx context.Context, igType InterfaceGroupType, cache map[string]*InterfaceGroup, mu *sync.RWMutex, name *string) *InterfaceGroup {
 	igName := "default"
 	if name != nil {
 		igName = *name
 	}
-	_, ok := a.NfsInterfaceGroups[igName]
+	mu.RLock()
+	_, ok := cache[igName]
+	mu.RUnlock()
 	if !ok {
-		err := a.fetchNfsInterfaceGroup(ctx, name, true)
+		var err error
+		if igType == InterfaceGroupTypeSMB {
+			err = a.fetchSmbInterfaceGroup(ctx, name, true)
+		} else {
+			err = a.fetchNfsInterfaceGroup(ctx, name, true)
+		}
 		if err != nil {
 			return nil
 		}
 	}
-	return a.NfsInterfaceGroups[igName]
+	mu.RLock()
+	defer mu.RUnlock()
+	return cache[igName]
+}
+
+func (a *ApiClient) GetNfsInterfaceGroup(ctx context.Context, name *string) *InterfaceGroup {
+	return a.getInterfaceGroup(ctx, InterfaceGroupTypeNFS, a.NfsInterfaceGroups, &a.nfsInterfaceGroupsMu, name)
+}
+
+// GetSmbInterfaceGroup returns the cached SMB interface group named name (or
+// the default one if name is nil), fetching it from the cluster first if
+// it isn't cached yet.
+func (a *ApiClient) GetSmbInterfaceGroup(ctx context.Context, name *string) *InterfaceGroup {
+	return a.getInterfaceGroup(ctx, InterfaceGroupTypeSMB, a.SmbInterfaceGroups, &a.smbInterfaceGroupsMu, name)
+}
+
+// getMountIp resolves the mount IP for either an NFS or SMB interface group,
+// sharing the selection-strategy/health-policy plumbing between both
+// protocols.
+func (a *ApiClient) getMountIp(ctx context.Context, interfaceGroupName *string, igType InterfaceGroupType) (string, error) {
+	var ig *InterfaceGroup
+	if igType == InterfaceGroupTypeSMB {
+		ig = a.GetSmbInterfaceGroup(ctx, interfaceGroupName)
+	} else {
+		ig = a.GetNfsInterfaceGroup(ctx, interfaceGroupName)
+	}
+	if ig == nil {
+		return "", fmt.Errorf("no %s interface group found", igType)
+	}
+	if len(ig.Ips) == 0 {
+		return "", fmt.Errorf("no IP addresses found for %s interface group", igType)
+	}
+
+	strategy := a.NfsIpSelectionStrategy
+	if strategy == "" {
+		strategy = DefaultNfsIpSelectionStrategy
+	}
+	return ig.GetIpAddress(strategy, a.healthTracker)
 }
 
-// GetNfsMountIp returns the IP address of the NFS interface group to be used for NFS mount
-// TODO: need to do it much more sophisticated way to distribute load
+// GetNfsMountIp returns the IP address of the NFS interface group to be used for NFS mount,
+// selected per the client's configured NfsIpSelectionStrategy (DefaultNfsIpSelectionStrategy
+// if unset). When health tracking is enabled, it picks among the IPs the client's health
+// tracker currently considers reachable, or among all IPs if none of them is.
 func (a *ApiClient) GetNfsMountIp(ctx context.Context, interfaceGroupName *string) (string, error) {
+	return a.getMountIp(ctx, interfaceGroupName, InterfaceGroupTypeNFS)
+}
+
+// GetSmbMountIp returns the IP address of the SMB interface group to be used for SMB mount,
+// using the same selection-strategy and health-policy plumbing as GetNfsMountIp.
+func (a *ApiClient) GetSmbMountIp(ctx context.Context, interfaceGroupName *string) (string, error) {
+	return a.getMountIp(ctx, interfaceGroupName, InterfaceGroupTypeSMB)
+}
+
+// GetNfsMountIps returns up to n distinct IP addresses for interfaceGroupName,
+// suitable for NFS clients using nconnect=n or multipath, where a single
+// deterministic IP (as returned by GetNfsMountIp) would pin every mount from
+// this node onto the same server.
+func (a *ApiClient) GetNfsMountIps(ctx context.Context, interfaceGroupName *string, n int) ([]string, error) {
 	ig := a.GetNfsInterfaceGroup(ctx, interfaceGroupName)
 	if ig == nil {
-		return "", errors.New("no NFS interface group found")
+		return nil, errors.New("no NFS interface group found")
 	}
-	if ig.Ips == nil || len(ig.Ips) == 0 {
-		return "", errors.New("no IP addresses found for NFS interface group")
+	if len(ig.Ips) == 0 {
+		return nil, errors.New("no IP addresses found for NFS interface group")
 	}
 
-	return ig.GetIpAddress()
+	strategy := a.NfsIpSelectionStrategy
+	if strategy == "" {
+		strategy = DefaultNfsIpSelectionStrategy
+	}
+	return ig.GetIpAddresses(n, strategy, a.healthTracker)
 }
@@ -0,0 +1,298 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultProbeTimeout      = 2 * time.Second
+	defaultProbeInterval     = 30 * time.Second
+	maxProbeBackoff          = 5 * time.Minute
+	defaultProbeLoopInterval = 15 * time.Second
+)
+
+// IpSelectionPolicy controls how a healthy IP is picked out of an
+// InterfaceGroup's candidate set once the unhealthy members have been
+// filtered out.
+type IpSelectionPolicy string
+
+const (
+	IpSelectionPolicyHash              IpSelectionPolicy = "Hash"
+	IpSelectionPolicyRoundRobin        IpSelectionPolicy = "RoundRobin"
+	IpSelectionPolicyLeastRecentlyUsed IpSelectionPolicy = "LeastRecentlyUsed"
+	IpSelectionPolicyWeightedRandom    IpSelectionPolicy = "WeightedRandom"
+)
+
+// UnhealthyIpCallback is invoked when an IP transitions from healthy to
+// unhealthy, so that a caller actively mounted on it (e.g. the CSI node
+// service) can trigger a remount onto a healthy replacement.
+type UnhealthyIpCallback func(ig *InterfaceGroup, ip string)
+
+type ipHealthState struct {
+	healthy             bool
+	consecutiveFailures int
+	nextProbeAt         time.Time
+	lastUsedAt          time.Time
+}
+
+// HealthTracker TCP-probes every IP of the InterfaceGroups cached on an
+// ApiClient and keeps track of which ones are currently reachable, so that
+// GetIpAddress/GetNfsMountIp prefer IPs of IG members that answer probes. When
+// every IP of a group is unhealthy, those functions fall back to the full IP
+// list. A single HealthTracker is shared across all interface groups on a
+// client.
+type HealthTracker struct {
+	mu           sync.Mutex
+	state        map[string]*ipHealthState
+	probeTimeout time.Duration
+	rrCounter    uint64
+
+	// ProbeSuccesses and ProbeFailures are cumulative counters intended to be
+	// surfaced as Prometheus counters by callers.
+	ProbeSuccesses uint64
+	ProbeFailures  uint64
+
+	// OnUnhealthy, if set, is called whenever a probe finds a previously
+	// healthy IP unreachable.
+	OnUnhealthy UnhealthyIpCallback
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// NewHealthTracker creates a HealthTracker that dials with probeTimeout.
+// probeTimeout <= 0 uses defaultProbeTimeout.
+func NewHealthTracker(probeTimeout time.Duration) *HealthTracker {
+	if probeTimeout <= 0 {
+		probeTimeout = defaultProbeTimeout
+	}
+	return &HealthTracker{
+		state:        make(map[string]*ipHealthState),
+		probeTimeout: probeTimeout,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start launches the background probe loop in its own goroutine and returns
+// immediately: on every tick it probes every IP of every interface group
+// currently cached on client. The loop stops when ctx is done or Stop is
+// called.
+func (h *HealthTracker) Start(ctx context.Context, client *ApiClient, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultProbeLoopInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.stopCh:
+				return
+			case <-ticker.C:
+				for _, ig := range client.cachedInterfaceGroups() {
+					h.ProbeAll(ig)
+				}
+			}
+		}
+	}()
+}
+
+// Stop terminates the background probe loop. Safe to call multiple times.
+func (h *HealthTracker) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+}
+
+// ProbePort returns the TCP port used to health-check this interface group's
+// member IPs, based on its protocol (2049 for NFS, 445 for SMB).
+func (i *InterfaceGroup) ProbePort() int {
+	if i.isSmb() {
+		return 445
+	}
+	return 2049
+}
+
+// probe dials ip on ig's protocol port once and updates its health state,
+// applying exponential backoff to the next probe time on repeated failures.
+func (h *HealthTracker) probe(ig *InterfaceGroup, ip string) {
+	addr := net.JoinHostPort(ip, fmt.Sprintf("%d", ig.ProbePort()))
+	conn, dialErr := net.DialTimeout("tcp", addr, h.probeTimeout)
+
+	h.mu.Lock()
+	s, ok := h.state[ip]
+	if !ok {
+		s = &ipHealthState{healthy: true}
+		h.state[ip] = s
+	}
+	wasHealthy := s.healthy
+
+	if dialErr != nil {
+		s.healthy = false
+		s.consecutiveFailures++
+		atomic.AddUint64(&h.ProbeFailures, 1)
+		backoff := maxProbeBackoff
+		if s.consecutiveFailures <= 16 {
+			backoff = defaultProbeInterval << uint(s.consecutiveFailures-1)
+		}
+		if backoff > maxProbeBackoff {
+			backoff = maxProbeBackoff
+		}
+		s.nextProbeAt = time.Now().Add(backoff)
+	} else {
+		_ = conn.Close()
+		s.healthy = true
+		s.consecutiveFailures = 0
+		s.nextProbeAt = time.Now().Add(defaultProbeInterval)
+		atomic.AddUint64(&h.ProbeSuccesses, 1)
+	}
+	h.mu.Unlock()
+
+	if wasHealthy && dialErr != nil && h.OnUnhealthy != nil {
+		h.OnUnhealthy(ig, ip)
+	}
+}
+
+// ProbeAll probes every IP of ig that is currently due for a check.
+func (h *HealthTracker) ProbeAll(ig *InterfaceGroup) {
+	now := time.Now()
+	for _, ip := range ig.Ips {
+		h.mu.Lock()
+		s, ok := h.state[ip]
+		h.mu.Unlock()
+		if ok && now.Before(s.nextProbeAt) {
+			continue
+		}
+		h.probe(ig, ip)
+	}
+}
+
+// IsHealthy reports whether ip is currently considered reachable. IPs that
+// have never been probed yet are assumed healthy so a cold start doesn't
+// starve the selection pool.
+func (h *HealthTracker) IsHealthy(ip string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.state[ip]
+	if !ok {
+		return true
+	}
+	return s.healthy
+}
+
+func (h *HealthTracker) markUsed(ip string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.state[ip]; ok {
+		s.lastUsedAt = time.Now()
+	}
+}
+
+// healthyIps filters ips down to the ones currently considered healthy.
+func (h *HealthTracker) healthyIps(ips []string) []string {
+	var healthy []string
+	for _, ip := range ips {
+		if h.IsHealthy(ip) {
+			healthy = append(healthy, ip)
+		}
+	}
+	return healthy
+}
+
+// pick selects one IP out of candidates according to policy.
+func (h *HealthTracker) pick(candidates []string, policy IpSelectionPolicy) string {
+	switch policy {
+	case IpSelectionPolicyRoundRobin:
+		n := atomic.AddUint64(&h.rrCounter, 1)
+		return candidates[int(n-1)%len(candidates)]
+	case IpSelectionPolicyLeastRecentlyUsed:
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		best := candidates[0]
+		var oldest time.Time
+		for idx, ip := range candidates {
+			var used time.Time
+			if s, ok := h.state[ip]; ok {
+				used = s.lastUsedAt
+			}
+			if idx == 0 || used.Before(oldest) {
+				oldest = used
+				best = ip
+			}
+		}
+		return best
+	case IpSelectionPolicyWeightedRandom:
+		return h.weightedPick(candidates)
+	default:
+		return candidates[0]
+	}
+}
+
+// weightFor returns the selection weight for ip: IPs with a cleaner recent
+// health history (fewer consecutive probe failures since their last success)
+// are weighted higher than ones that have been flapping. IPs with no probe
+// history yet get the baseline weight of 1.
+func (h *HealthTracker) weightFor(ip string) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.state[ip]; ok {
+		return 1 / float64(1+s.consecutiveFailures)
+	}
+	return 1
+}
+
+// weightedPick selects one IP out of candidates at random, weighted by
+// weightFor.
+func (h *HealthTracker) weightedPick(candidates []string) string {
+	weights := make([]float64, len(candidates))
+	var total float64
+	for idx, ip := range candidates {
+		weights[idx] = h.weightFor(ip)
+		total += weights[idx]
+	}
+
+	r := rand.Float64() * total
+	for idx, w := range weights {
+		r -= w
+		if r <= 0 {
+			return candidates[idx]
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// weightedOrder returns candidates reordered by repeated weighted-random
+// sampling without replacement, per weightFor.
+func (h *HealthTracker) weightedOrder(candidates []string) []string {
+	remaining := append([]string{}, candidates...)
+	ordered := make([]string, 0, len(candidates))
+	for len(remaining) > 0 {
+		picked := h.weightedPick(remaining)
+		ordered = append(ordered, picked)
+		for idx, ip := range remaining {
+			if ip == picked {
+				remaining = append(remaining[:idx], remaining[idx+1:]...)
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+// HealthySnapshot returns the subset of this interface group's IPs that are
+// currently considered healthy by tracker. A nil tracker reports every IP as
+// healthy.
+func (i *InterfaceGroup) HealthySnapshot(tracker *HealthTracker) []string {
+	if tracker == nil {
+		return i.Ips
+	}
+	return tracker.healthyIps(i.Ips)
+}
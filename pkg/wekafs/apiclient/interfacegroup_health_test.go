@@ -0,0 +1,96 @@
+package apiclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthTrackerIsHealthyDefaultsToTrue(t *testing.T) {
+	h := NewHealthTracker(0)
+	if !h.IsHealthy("10.0.0.1") {
+		t.Error("an IP with no probe history should be considered healthy")
+	}
+}
+
+func TestHealthTrackerHealthyIpsFiltersUnhealthy(t *testing.T) {
+	h := NewHealthTracker(0)
+	h.state["10.0.0.1"] = &ipHealthState{healthy: true}
+	h.state["10.0.0.2"] = &ipHealthState{healthy: false}
+
+	got := h.healthyIps([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	want := []string{"10.0.0.1", "10.0.0.3"}
+	if len(got) != len(want) {
+		t.Fatalf("healthyIps() = %v, want %v", got, want)
+	}
+	for idx, ip := range want {
+		if got[idx] != ip {
+			t.Errorf("healthyIps()[%d] = %s, want %s", idx, got[idx], ip)
+		}
+	}
+}
+
+func TestHealthTrackerPickRoundRobinCycles(t *testing.T) {
+	h := NewHealthTracker(0)
+	candidates := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+
+	var picks []string
+	for i := 0; i < 6; i++ {
+		picks = append(picks, h.pick(candidates, IpSelectionPolicyRoundRobin))
+	}
+
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	for idx, ip := range want {
+		if picks[idx] != ip {
+			t.Errorf("pick #%d = %s, want %s (picks=%v)", idx, picks[idx], ip, picks)
+		}
+	}
+}
+
+func TestHealthTrackerPickLeastRecentlyUsed(t *testing.T) {
+	h := NewHealthTracker(0)
+	now := time.Now()
+	h.state["10.0.0.1"] = &ipHealthState{healthy: true, lastUsedAt: now}
+	h.state["10.0.0.2"] = &ipHealthState{healthy: true, lastUsedAt: now.Add(-time.Hour)}
+	h.state["10.0.0.3"] = &ipHealthState{healthy: true, lastUsedAt: now.Add(-time.Minute)}
+
+	got := h.pick([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, IpSelectionPolicyLeastRecentlyUsed)
+	if got != "10.0.0.2" {
+		t.Errorf("pick(LeastRecentlyUsed) = %s, want 10.0.0.2", got)
+	}
+}
+
+func TestHealthTrackerWeightForPrefersCleanHistory(t *testing.T) {
+	h := NewHealthTracker(0)
+	h.state["10.0.0.1"] = &ipHealthState{healthy: true, consecutiveFailures: 0}
+	h.state["10.0.0.2"] = &ipHealthState{healthy: true, consecutiveFailures: 3}
+
+	clean := h.weightFor("10.0.0.1")
+	flaky := h.weightFor("10.0.0.2")
+	unseen := h.weightFor("10.0.0.3")
+
+	if clean <= flaky {
+		t.Errorf("weightFor(clean)=%v should be greater than weightFor(flaky)=%v", clean, flaky)
+	}
+	if unseen != 1 {
+		t.Errorf("weightFor(unseen) = %v, want 1", unseen)
+	}
+}
+
+func TestHealthTrackerWeightedOrderIsAPermutation(t *testing.T) {
+	h := NewHealthTracker(0)
+	candidates := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+
+	ordered := h.weightedOrder(candidates)
+	if len(ordered) != len(candidates) {
+		t.Fatalf("weightedOrder() returned %d IPs, want %d", len(ordered), len(candidates))
+	}
+	seen := make(map[string]bool, len(ordered))
+	for _, ip := range ordered {
+		seen[ip] = true
+	}
+	for _, ip := range candidates {
+		if !seen[ip] {
+			t.Errorf("weightedOrder() dropped %s", ip)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package apiclient
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestInterfaceGroupSubnetFor(t *testing.T) {
+	ig := &InterfaceGroup{SubnetMask: "255.255.255.0"}
+
+	got := ig.subnetFor("10.0.1.5")
+	if got == nil {
+		t.Fatal("expected a non-nil network")
+	}
+	if want := "10.0.1.0"; !got.IP.Equal(net.ParseIP(want)) {
+		t.Errorf("network IP = %s, want %s", got.IP, want)
+	}
+	if !reflect.DeepEqual(got.Mask, net.IPMask(net.ParseIP("255.255.255.0").To4())) {
+		t.Errorf("unexpected mask: %v", got.Mask)
+	}
+}
+
+func TestInterfaceGroupSubnetForInvalid(t *testing.T) {
+	ig := &InterfaceGroup{SubnetMask: "not-a-mask"}
+	if got := ig.subnetFor("10.0.1.5"); got != nil {
+		t.Errorf("expected nil network for invalid mask, got %v", got)
+	}
+	ig.SubnetMask = "255.255.255.0"
+	if got := ig.subnetFor("not-an-ip"); got != nil {
+		t.Errorf("expected nil network for invalid ip, got %v", got)
+	}
+}
+
+func TestInterfaceGroupLocalityMatchedIps(t *testing.T) {
+	ig := &InterfaceGroup{
+		SubnetMask: "255.255.255.0",
+		Ips:        []string{"10.0.2.5", "10.0.1.5", "10.0.1.6"},
+	}
+	localNets := []*net.IPNet{
+		{IP: net.ParseIP("10.0.1.50").To4(), Mask: net.CIDRMask(24, 32)},
+	}
+
+	matched := ig.localityMatchedIps(localNets)
+
+	want := []string{"10.0.1.5", "10.0.1.6"}
+	if !reflect.DeepEqual(matched, want) {
+		t.Errorf("localityMatchedIps() = %v, want %v", matched, want)
+	}
+}
+
+func TestInterfaceGroupLocalityMatchedIpsNoMatch(t *testing.T) {
+	ig := &InterfaceGroup{
+		SubnetMask: "255.255.255.0",
+		Ips:        []string{"10.0.2.5", "10.0.2.6"},
+	}
+	localNets := []*net.IPNet{
+		{IP: net.ParseIP("10.0.1.50").To4(), Mask: net.CIDRMask(24, 32)},
+	}
+
+	if matched := ig.localityMatchedIps(localNets); len(matched) != 0 {
+		t.Errorf("expected no matches, got %v", matched)
+	}
+}
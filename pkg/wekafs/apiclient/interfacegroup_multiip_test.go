@@ -0,0 +1,77 @@
+package apiclient
+
+import "testing"
+
+func TestRotateByHashIsStablePerHostname(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+
+	first := rotateByHash(ips, "node-a")
+	second := rotateByHash(ips, "node-a")
+	if len(first) != len(ips) || len(second) != len(ips) {
+		t.Fatalf("rotateByHash changed the element count: %v / %v", first, second)
+	}
+	for idx := range first {
+		if first[idx] != second[idx] {
+			t.Fatalf("rotateByHash(%q) is not deterministic: %v != %v", "node-a", first, second)
+		}
+	}
+
+	seen := make(map[string]bool, len(first))
+	for _, ip := range first {
+		seen[ip] = true
+	}
+	for _, ip := range ips {
+		if !seen[ip] {
+			t.Errorf("rotateByHash dropped %s", ip)
+		}
+	}
+}
+
+func TestContainsIp(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2"}
+	if !containsIp(ips, "10.0.0.1") {
+		t.Error("expected containsIp to find an existing IP")
+	}
+	if containsIp(ips, "10.0.0.3") {
+		t.Error("expected containsIp to not find a missing IP")
+	}
+}
+
+func TestGetIpAddressesDeduplicatesAndTruncates(t *testing.T) {
+	ig := &InterfaceGroup{Ips: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}
+
+	got, err := ig.GetIpAddresses(2, NfsIpSelectionStrategyHash, nil)
+	if err != nil {
+		t.Fatalf("GetIpAddresses() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetIpAddresses(2) returned %d IPs, want 2: %v", len(got), got)
+	}
+
+	seen := make(map[string]bool, len(got))
+	for _, ip := range got {
+		if seen[ip] {
+			t.Errorf("GetIpAddresses returned a duplicate IP: %v", got)
+		}
+		seen[ip] = true
+	}
+}
+
+func TestGetIpAddressesDegradesGracefullyWhenFewerThanN(t *testing.T) {
+	ig := &InterfaceGroup{Ips: []string{"10.0.0.1", "10.0.0.2"}}
+
+	got, err := ig.GetIpAddresses(5, NfsIpSelectionStrategyHash, nil)
+	if err != nil {
+		t.Fatalf("GetIpAddresses() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetIpAddresses(5) with only 2 IPs available returned %d IPs, want 2: %v", len(got), got)
+	}
+}
+
+func TestGetIpAddressesRejectsNonPositiveN(t *testing.T) {
+	ig := &InterfaceGroup{Ips: []string{"10.0.0.1"}}
+	if _, err := ig.GetIpAddresses(0, NfsIpSelectionStrategyHash, nil); err == nil {
+		t.Error("expected an error when n <= 0")
+	}
+}
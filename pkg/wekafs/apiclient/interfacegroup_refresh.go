@@ -0,0 +1,336 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultRefreshInterval is how often a Refresher re-fetches cached
+// interface groups from the cluster when no interval is configured.
+const defaultRefreshInterval = 60 * time.Second
+
+// defaultKeepRouteGracePeriod mirrors the "keep_route" option of DNS-based
+// route managers such as netbird: an IP that just disappeared from an
+// interface group stays in the selection pool for this long, so mounts
+// already using it don't break mid-flight.
+const defaultKeepRouteGracePeriod = 2 * time.Minute
+
+// InterfaceGroupChange describes the diff observed between two consecutive
+// refreshes of the same cached InterfaceGroup.
+type InterfaceGroupChange struct {
+	Name              string
+	Type              InterfaceGroupType
+	AddedIps          []string
+	RemovedIps        []string
+	StatusChanged     bool
+	GatewayChanged    bool
+	SubnetMaskChanged bool
+}
+
+func (c InterfaceGroupChange) isEmpty() bool {
+	return len(c.AddedIps) == 0 && len(c.RemovedIps) == 0 &&
+		!c.StatusChanged && !c.GatewayChanged && !c.SubnetMaskChanged
+}
+
+// diffInterfaceGroups computes the InterfaceGroupChange between the
+// previously cached version of an interface group and a freshly fetched one.
+func diffInterfaceGroups(old, new *InterfaceGroup) InterfaceGroupChange {
+	change := InterfaceGroupChange{Name: new.Name, Type: new.Type}
+
+	oldIps := make(map[string]struct{}, len(old.Ips))
+	for _, ip := range old.Ips {
+		oldIps[ip] = struct{}{}
+	}
+	newIps := make(map[string]struct{}, len(new.Ips))
+	for _, ip := range new.Ips {
+		newIps[ip] = struct{}{}
+	}
+	for ip := range newIps {
+		if _, ok := oldIps[ip]; !ok {
+			change.AddedIps = append(change.AddedIps, ip)
+		}
+	}
+	for ip := range oldIps {
+		if _, ok := newIps[ip]; !ok {
+			change.RemovedIps = append(change.RemovedIps, ip)
+		}
+	}
+
+	change.StatusChanged = old.Status != new.Status
+	change.GatewayChanged = old.Gateway != new.Gateway
+	change.SubnetMaskChanged = old.SubnetMask != new.SubnetMask
+	return change
+}
+
+// Refresher periodically re-fetches every InterfaceGroup cached on an
+// ApiClient and publishes what changed on Changes, mirroring the periodic
+// DNS-route re-resolution pattern used by netbird.
+type Refresher struct {
+	client   *ApiClient
+	interval time.Duration
+
+	// KeepRouteGracePeriod keeps an IP that disappeared from an interface
+	// group in the selection pool for this long after the change is
+	// detected, so in-flight mounts have time to transition to a
+	// replacement (analogous to netbird's keep_route option).
+	KeepRouteGracePeriod time.Duration
+
+	// Changes receives one InterfaceGroupChange per refresh cycle for every
+	// cached interface group whose IPs, status, gateway or subnet mask
+	// differ from what was previously cached. It is buffered and
+	// non-blocking: a slow consumer drops changes rather than stalling
+	// the refresh loop.
+	Changes chan InterfaceGroupChange
+
+	// retiring tracks, per interface group (keyed by "<type>/<cache key>"),
+	// which of its IPs are within their keep-route grace period and when
+	// that grace period expires. Scoping by interface group keeps an IP
+	// that disappeared from one IG from leaking into another IG's pool.
+	retiringMu sync.Mutex
+	retiring   map[string]map[string]time.Time
+
+	// lastFetchMu guards lastFetch, which holds the true (pre-keep-route)
+	// API response from the previous refresh of each interface group, keyed
+	// the same way as retiring. Diffs are computed against this rather than
+	// against the publicly-cached value, which may itself have grace-period
+	// IPs spliced in: diffing against the cache would keep reporting an
+	// already-removed IP as newly removed on every cycle and the grace
+	// period would never lapse.
+	lastFetchMu sync.Mutex
+	lastFetch   map[string]*InterfaceGroup
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// lastFetchFor returns the true API response observed for scope on the
+// previous refresh, or nil if this is the first refresh.
+func (r *Refresher) lastFetchFor(scope string) *InterfaceGroup {
+	r.lastFetchMu.Lock()
+	defer r.lastFetchMu.Unlock()
+	return r.lastFetch[scope]
+}
+
+// setLastFetch records ig as the true API response observed for scope on
+// this refresh, for the next refresh to diff against.
+func (r *Refresher) setLastFetch(scope string, ig *InterfaceGroup) {
+	r.lastFetchMu.Lock()
+	defer r.lastFetchMu.Unlock()
+	if r.lastFetch == nil {
+		r.lastFetch = make(map[string]*InterfaceGroup)
+	}
+	r.lastFetch[scope] = ig
+}
+
+// igScope builds the key used to scope Refresher.retiring to a single
+// interface group.
+func igScope(igType InterfaceGroupType, cacheKey string) string {
+	return string(igType) + "/" + cacheKey
+}
+
+// NewRefresher creates a Refresher for client. interval <= 0 uses
+// defaultRefreshInterval.
+func NewRefresher(client *ApiClient, interval time.Duration) *Refresher {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	return &Refresher{
+		client:               client,
+		interval:             interval,
+		KeepRouteGracePeriod: defaultKeepRouteGracePeriod,
+		Changes:              make(chan InterfaceGroupChange, 16),
+		stopCh:               make(chan struct{}),
+	}
+}
+
+// Start launches the background refresh loop in its own goroutine and
+// returns immediately. The loop stops when ctx is done or Stop is called.
+func (r *Refresher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				_ = r.client.RefreshInterfaceGroups(ctx)
+			}
+		}
+	}()
+}
+
+// Stop terminates the background refresh loop. Safe to call multiple times.
+func (r *Refresher) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// noteRemoved records ips that just disappeared from the interface group
+// identified by scope, so they keep being offered as mount targets for that
+// same interface group for KeepRouteGracePeriod.
+func (r *Refresher) noteRemoved(scope string, ips []string) {
+	if len(ips) == 0 {
+		return
+	}
+	r.retiringMu.Lock()
+	defer r.retiringMu.Unlock()
+	if r.retiring == nil {
+		r.retiring = make(map[string]map[string]time.Time)
+	}
+	igRetiring, ok := r.retiring[scope]
+	if !ok {
+		igRetiring = make(map[string]time.Time)
+		r.retiring[scope] = igRetiring
+	}
+	expiry := time.Now().Add(r.KeepRouteGracePeriod)
+	for _, ip := range ips {
+		igRetiring[ip] = expiry
+	}
+}
+
+// retiredIps returns the IPs still within their keep-route grace period for
+// the interface group identified by scope, pruning any that have expired.
+func (r *Refresher) retiredIps(scope string) []string {
+	r.retiringMu.Lock()
+	defer r.retiringMu.Unlock()
+	igRetiring, ok := r.retiring[scope]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	var alive []string
+	for ip, expiry := range igRetiring {
+		if now.After(expiry) {
+			delete(igRetiring, ip)
+			continue
+		}
+		alive = append(alive, ip)
+	}
+	if len(igRetiring) == 0 {
+		delete(r.retiring, scope)
+	}
+	return alive
+}
+
+// EnableInterfaceGroupRefresh creates a Refresher, attaches it to the client
+// so refreshes start publishing change events and honoring the keep-route
+// grace period, and starts its background refresh loop. interval <= 0 uses
+// defaultRefreshInterval.
+func (a *ApiClient) EnableInterfaceGroupRefresh(ctx context.Context, interval time.Duration) *Refresher {
+	r := NewRefresher(a, interval)
+	a.refresher = r
+	r.Start(ctx)
+	return r
+}
+
+// RefreshInterfaceGroups re-fetches every NFS and SMB interface group
+// currently cached on the client and publishes a change event, via the
+// client's Refresher if one is attached, for each one whose IPs, status,
+// gateway or subnet mask differ from the previous refresh's API response (or,
+// on the first refresh, from what was cached before).
+func (a *ApiClient) RefreshInterfaceGroups(ctx context.Context) error {
+	a.nfsInterfaceGroupsMu.RLock()
+	nfsNames := make([]string, 0, len(a.NfsInterfaceGroups))
+	for name := range a.NfsInterfaceGroups {
+		nfsNames = append(nfsNames, name)
+	}
+	a.nfsInterfaceGroupsMu.RUnlock()
+
+	for _, name := range nfsNames {
+		if err := a.refreshInterfaceGroup(ctx, InterfaceGroupTypeNFS, a.NfsInterfaceGroups, &a.nfsInterfaceGroupsMu, name); err != nil {
+			return err
+		}
+	}
+
+	a.smbInterfaceGroupsMu.RLock()
+	smbNames := make([]string, 0, len(a.SmbInterfaceGroups))
+	for name := range a.SmbInterfaceGroups {
+		smbNames = append(smbNames, name)
+	}
+	a.smbInterfaceGroupsMu.RUnlock()
+
+	for _, name := range smbNames {
+		if err := a.refreshInterfaceGroup(ctx, InterfaceGroupTypeSMB, a.SmbInterfaceGroups, &a.smbInterfaceGroupsMu, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshInterfaceGroup re-fetches a single cached interface group of the
+// given type by its cache key (either a real IG name or "default"), diffs it
+// against what was cached, and swaps in the new value. Removed IPs are kept
+// in the pool for the attached Refresher's KeepRouteGracePeriod, if any. It
+// is the shared implementation behind RefreshInterfaceGroups for both NFS and
+// SMB caches.
+func (a *ApiClient) refreshInterfaceGroup(ctx context.Context, igType InterfaceGroupType, cache map[string]*InterfaceGroup, mu *sync.RWMutex, cacheKey string) error {
+	mu.RLock()
+	previous := cache[cacheKey]
+	mu.RUnlock()
+	if previous == nil {
+		return nil
+	}
+
+	igs := &[]InterfaceGroup{}
+	if err := a.GetInterfaceGroupsByType(ctx, igType, igs); err != nil {
+		return errors.Join(fmt.Errorf("failed to refresh %s interface groups", igType), err)
+	}
+
+	var fresh *InterfaceGroup
+	for idx := range *igs {
+		if (*igs)[idx].Name == previous.Name {
+			fresh = &(*igs)[idx]
+			break
+		}
+	}
+	if fresh == nil {
+		return nil
+	}
+	sort.Strings(fresh.Ips)
+
+	// Diff against the true previous API response, not the publicly-cached
+	// value: the cache may already carry grace-period IPs spliced in by a
+	// prior cycle, and diffing against that would keep re-reporting an
+	// already-removed IP as newly removed forever, so its grace period would
+	// never lapse.
+	baseline := previous
+	if a.refresher != nil {
+		if last := a.refresher.lastFetchFor(igScope(igType, cacheKey)); last != nil {
+			baseline = last
+		}
+	}
+	change := diffInterfaceGroups(baseline, fresh)
+
+	if a.refresher != nil {
+		scope := igScope(igType, cacheKey)
+		trueFetch := *fresh
+		trueFetch.Ips = append([]string{}, fresh.Ips...)
+		a.refresher.setLastFetch(scope, &trueFetch)
+
+		a.refresher.noteRemoved(scope, change.RemovedIps)
+		if grace := a.refresher.retiredIps(scope); len(grace) > 0 {
+			fresh.Ips = append(append([]string{}, fresh.Ips...), grace...)
+			sort.Strings(fresh.Ips)
+		}
+	}
+
+	mu.Lock()
+	cache[cacheKey] = fresh
+	mu.Unlock()
+
+	if a.refresher != nil && !change.isEmpty() {
+		select {
+		case a.refresher.Changes <- change:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package apiclient
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiffInterfaceGroupsDetectsIpChurn(t *testing.T) {
+	old := &InterfaceGroup{
+		Name: "default",
+		Type: InterfaceGroupTypeNFS,
+		Ips:  []string{"10.0.0.1", "10.0.0.2"},
+	}
+	new := &InterfaceGroup{
+		Name: "default",
+		Type: InterfaceGroupTypeNFS,
+		Ips:  []string{"10.0.0.2", "10.0.0.3"},
+	}
+
+	change := diffInterfaceGroups(old, new)
+
+	sort.Strings(change.AddedIps)
+	sort.Strings(change.RemovedIps)
+	if !reflect.DeepEqual(change.AddedIps, []string{"10.0.0.3"}) {
+		t.Errorf("AddedIps = %v, want [10.0.0.3]", change.AddedIps)
+	}
+	if !reflect.DeepEqual(change.RemovedIps, []string{"10.0.0.1"}) {
+		t.Errorf("RemovedIps = %v, want [10.0.0.1]", change.RemovedIps)
+	}
+	if change.isEmpty() {
+		t.Error("change should not be empty")
+	}
+}
+
+func TestDiffInterfaceGroupsDetectsMetadataChanges(t *testing.T) {
+	old := &InterfaceGroup{Ips: []string{"10.0.0.1"}, Status: "OK", Gateway: "10.0.0.254", SubnetMask: "255.255.255.0"}
+	new := &InterfaceGroup{Ips: []string{"10.0.0.1"}, Status: "DOWN", Gateway: "10.0.0.253", SubnetMask: "255.255.0.0"}
+
+	change := diffInterfaceGroups(old, new)
+
+	if !change.StatusChanged || !change.GatewayChanged || !change.SubnetMaskChanged {
+		t.Errorf("expected all metadata fields to be flagged as changed: %+v", change)
+	}
+	if len(change.AddedIps) != 0 || len(change.RemovedIps) != 0 {
+		t.Errorf("did not expect any IP churn: %+v", change)
+	}
+}
+
+func TestDiffInterfaceGroupsNoChangeIsEmpty(t *testing.T) {
+	old := &InterfaceGroup{Ips: []string{"10.0.0.1"}, Status: "OK"}
+	new := &InterfaceGroup{Ips: []string{"10.0.0.1"}, Status: "OK"}
+
+	change := diffInterfaceGroups(old, new)
+	if !change.isEmpty() {
+		t.Errorf("expected no change to be reported, got %+v", change)
+	}
+}
+
+func TestRefresherLastFetchTracksTruePriorResponse(t *testing.T) {
+	r := NewRefresher(&ApiClient{}, 0)
+	scope := igScope(InterfaceGroupTypeNFS, "default")
+
+	if got := r.lastFetchFor(scope); got != nil {
+		t.Fatalf("lastFetchFor before any fetch = %+v, want nil", got)
+	}
+
+	first := &InterfaceGroup{Name: "default", Ips: []string{"10.0.0.1", "10.0.0.2"}}
+	r.setLastFetch(scope, first)
+	if got := r.lastFetchFor(scope); !reflect.DeepEqual(got.Ips, first.Ips) {
+		t.Errorf("lastFetchFor = %+v, want %+v", got, first)
+	}
+
+	// A later refresh augments the publicly-cached Ips with a grace-period
+	// IP, but that augmented value must never become the diff baseline: only
+	// setLastFetch should move lastFetchFor forward, and it must be fed the
+	// true API response, not the grace-augmented one.
+	second := &InterfaceGroup{Name: "default", Ips: []string{"10.0.0.2"}}
+	r.setLastFetch(scope, second)
+	if got := r.lastFetchFor(scope); !reflect.DeepEqual(got.Ips, second.Ips) {
+		t.Errorf("lastFetchFor after second fetch = %+v, want %+v", got, second)
+	}
+
+	// Diffing the stored baseline against a third fetch that still lacks
+	// 10.0.0.1 must not report it as removed again: it was already reported
+	// removed on the second fetch, and its grace period should be allowed to
+	// run out rather than being restarted forever.
+	third := &InterfaceGroup{Name: "default", Ips: []string{"10.0.0.2"}}
+	change := diffInterfaceGroups(r.lastFetchFor(scope), third)
+	if !change.isEmpty() {
+		t.Errorf("expected no further change once baseline tracks the true prior fetch, got %+v", change)
+	}
+}
+
+func TestRefresherRetiringIsScopedPerInterfaceGroup(t *testing.T) {
+	r := NewRefresher(&ApiClient{}, 0)
+
+	westScope := igScope(InterfaceGroupTypeNFS, "ig-west")
+	eastScope := igScope(InterfaceGroupTypeNFS, "ig-east")
+
+	r.noteRemoved(westScope, []string{"10.0.0.1"})
+
+	if got := r.retiredIps(westScope); len(got) != 1 || got[0] != "10.0.0.1" {
+		t.Errorf("retiredIps(west) = %v, want [10.0.0.1]", got)
+	}
+	if got := r.retiredIps(eastScope); len(got) != 0 {
+		t.Errorf("retiredIps(east) = %v, want none: an IP removed from ig-west must not leak into ig-east", got)
+	}
+}
@@ -0,0 +1,23 @@
+package apiclient
+
+import "testing"
+
+func TestRequiresSmbCredentials(t *testing.T) {
+	cases := []struct {
+		name string
+		ig   InterfaceGroup
+		want bool
+	}{
+		{"smb requiring credentials", InterfaceGroup{Type: InterfaceGroupTypeSMB, SmbRequiresCredentials: true}, true},
+		{"smb not requiring credentials", InterfaceGroup{Type: InterfaceGroupTypeSMB, SmbRequiresCredentials: false}, false},
+		{"nfs ignores the flag", InterfaceGroup{Type: InterfaceGroupTypeNFS, SmbRequiresCredentials: true}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.ig.RequiresSmbCredentials(); got != c.want {
+				t.Errorf("RequiresSmbCredentials() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}